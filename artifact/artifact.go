@@ -0,0 +1,79 @@
+// Package artifact 把"一个manifest是什么"这件事从media type的特判中解耦出来。
+// Resolver按manifest的media type注册，Resolve出的Artifact暴露类型无关的
+// kind/tags/references/extra，供上层（比如registry API）统一消费，而不需要
+// 为每一种新的manifest格式（image、CNAB bundle、……）单独写分支。
+package artifact
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sequix/distribution"
+)
+
+// Kind 标识一个Artifact的类型，比如"image"、"cnab.bundle"。
+type Kind string
+
+// KindImage 是piddle/OCI镜像对应的artifact kind。
+const KindImage Kind = "image"
+
+// Artifact 是Resolver从一个已解析的distribution.Manifest中提炼出的、
+// media type无关的元数据视图。
+type Artifact interface {
+	// Kind 返回该artifact的类型。
+	Kind() Kind
+
+	// Tags 返回该artifact自身声明的tag（如果有），没有则返回nil。
+	Tags() []string
+
+	// References 返回该artifact引用的所有对象（layers、子manifest等）。
+	References() []distribution.Descriptor
+
+	// Extra 返回该artifact类型特有、不适合放进上面几个字段的元数据。
+	Extra() map[string]interface{}
+}
+
+// Resolver 把一个已解析出的distribution.Manifest转换为Artifact。
+type Resolver interface {
+	Resolve(ctx context.Context, repo distribution.Repository, manifest distribution.Manifest) (Artifact, error)
+}
+
+// resolvers按manifest的media type索引Resolver，与distribution.mappings的
+// 注册方式保持一致。
+var resolvers = make(map[string]Resolver)
+
+// RegisterResolver registers a Resolver for a given manifest media type. This
+// should be called from the package implementing that manifest type's schema,
+// mirroring distribution.RegisterManifestSchema.
+func RegisterResolver(mediaType string, r Resolver) error {
+	if _, ok := resolvers[mediaType]; ok {
+		return fmt.Errorf("artifact resolver registration would overwrite existing: %s", mediaType)
+	}
+	resolvers[mediaType] = r
+	return nil
+}
+
+// ErrNoResolver is returned by Resolve when no Resolver is registered for a
+// manifest's media type.
+type ErrNoResolver struct {
+	MediaType string
+}
+
+func (e ErrNoResolver) Error() string {
+	return fmt.Sprintf("artifact: no resolver registered for media type: %s", e.MediaType)
+}
+
+// Resolve looks up the Resolver registered for manifest's media type and uses
+// it to produce an Artifact.
+func Resolve(ctx context.Context, repo distribution.Repository, manifest distribution.Manifest) (Artifact, error) {
+	mediaType, _, err := manifest.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := resolvers[mediaType]
+	if !ok {
+		return nil, ErrNoResolver{MediaType: mediaType}
+	}
+	return r.Resolve(ctx, repo, manifest)
+}
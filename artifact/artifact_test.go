@@ -0,0 +1,72 @@
+package artifact
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sequix/distribution"
+)
+
+// stubManifest implements distribution.Manifest with a fixed media type;
+// none of RegisterResolver/Resolve ever inspect References()/Payload's
+// byte contents, only the media type returned by Payload.
+type stubManifest struct {
+	mediaType string
+}
+
+func (m stubManifest) References() []distribution.Descriptor { return nil }
+
+func (m stubManifest) Payload() (string, []byte, error) {
+	return m.mediaType, nil, nil
+}
+
+// stubResolver returns a fixed Artifact, ignoring its arguments.
+type stubResolver struct {
+	artifact Artifact
+}
+
+func (r stubResolver) Resolve(ctx context.Context, repo distribution.Repository, m distribution.Manifest) (Artifact, error) {
+	return r.artifact, nil
+}
+
+type stubArtifact struct{}
+
+func (stubArtifact) Kind() Kind                            { return Kind("stub") }
+func (stubArtifact) Tags() []string                        { return nil }
+func (stubArtifact) References() []distribution.Descriptor { return nil }
+func (stubArtifact) Extra() map[string]interface{}         { return nil }
+
+func TestRegisterResolverRejectsDuplicateMediaType(t *testing.T) {
+	const mediaType = "application/vnd.sequix.artifact-test.v1+json"
+
+	if err := RegisterResolver(mediaType, stubResolver{artifact: stubArtifact{}}); err != nil {
+		t.Fatalf("first RegisterResolver: %v", err)
+	}
+	if err := RegisterResolver(mediaType, stubResolver{artifact: stubArtifact{}}); err == nil {
+		t.Fatal("second RegisterResolver for the same media type should error")
+	}
+}
+
+func TestResolveUsesRegisteredResolver(t *testing.T) {
+	const mediaType = "application/vnd.sequix.artifact-test.resolve.v1+json"
+	want := stubArtifact{}
+
+	if err := RegisterResolver(mediaType, stubResolver{artifact: want}); err != nil {
+		t.Fatalf("RegisterResolver: %v", err)
+	}
+
+	got, err := Resolve(context.Background(), nil, stubManifest{mediaType: mediaType})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if got != Artifact(want) {
+		t.Errorf("Resolve() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolveNoRegisteredResolver(t *testing.T) {
+	_, err := Resolve(context.Background(), nil, stubManifest{mediaType: "application/vnd.sequix.artifact-test.unregistered.v1+json"})
+	if _, ok := err.(ErrNoResolver); !ok {
+		t.Fatalf("err = %v (%T), want ErrNoResolver", err, err)
+	}
+}
@@ -0,0 +1,174 @@
+// Package cnab 演示artifact.RegisterResolver这个扩展点：不修改registry的任何
+// 既有代码，就能让它认识一种全新的、非镜像的manifest格式——CNAB bundle。
+package cnab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/artifact"
+	"github.com/sequix/distribution/manifest"
+)
+
+// MediaTypeManifest 是CNAB bundle manifest自身的媒体类型。
+const MediaTypeManifest = "application/vnd.cnab.manifest.v1+json"
+
+// KindBundle 是CNAB bundle对应的artifact kind。
+const KindBundle artifact.Kind = "cnab.bundle"
+
+var (
+	// SchemaVersion provides a pre-initialized version structure for this
+	// package's version of the manifest.
+	SchemaVersion = manifest.Versioned{
+		SchemaVersion: 1,
+		MediaType:     MediaTypeManifest,
+	}
+)
+
+func init() {
+	unmarshalFunc := func(b []byte) (distribution.Manifest, distribution.Descriptor, error) {
+		m := new(DeserializedManifest)
+		if err := m.UnmarshalJSON(b); err != nil {
+			return nil, distribution.Descriptor{}, err
+		}
+
+		dgst := digest.FromBytes(b)
+		return m, distribution.Descriptor{Digest: dgst, Size: int64(len(b)), MediaType: MediaTypeManifest}, nil
+	}
+	if err := distribution.RegisterManifestSchema(MediaTypeManifest, unmarshalFunc); err != nil {
+		panic(fmt.Sprintf("Unable to register manifest: %s", err))
+	}
+	if err := artifact.RegisterResolver(MediaTypeManifest, bundleResolver{}); err != nil {
+		panic(fmt.Sprintf("Unable to register artifact resolver: %s", err))
+	}
+}
+
+// Manifest 是CNAB bundle在registry中的清单：Config指向bundle.json，References
+// 列出该bundle依赖的invocation images及其它制品。
+type Manifest struct {
+	manifest.Versioned
+
+	// Config references the bundle.json describing the CNAB bundle.
+	Config distribution.Descriptor `json:"config"`
+
+	// References lists the invocation images and other artifacts the
+	// bundle depends on.
+	References []distribution.Descriptor `json:"references"`
+}
+
+// referencesList builds the combined config+references descriptor list
+// backing both DeserializedManifest.References() and bundleArtifact's own
+// References(); named to avoid clashing with the exported References field.
+func (m Manifest) referencesList() []distribution.Descriptor {
+	refs := make([]distribution.Descriptor, 0, 1+len(m.References))
+	refs = append(refs, m.Config)
+	refs = append(refs, m.References...)
+	return refs
+}
+
+// DeserializedManifest wraps Manifest with a copy of the original JSON.
+// It satisfies the distribution.Manifest interface.
+type DeserializedManifest struct {
+	Manifest
+
+	// canonical is the canonical byte representation of the Manifest.
+	canonical []byte
+}
+
+// References returns the descriptors of this manifest's references.
+func (m DeserializedManifest) References() []distribution.Descriptor {
+	return m.Manifest.referencesList()
+}
+
+// UnmarshalJSON populates a new Manifest struct from JSON data.
+func (m *DeserializedManifest) UnmarshalJSON(b []byte) error {
+	m.canonical = make([]byte, len(b))
+	copy(m.canonical, b)
+
+	var manifest Manifest
+	if err := json.Unmarshal(m.canonical, &manifest); err != nil {
+		return err
+	}
+
+	if manifest.MediaType != MediaTypeManifest {
+		return fmt.Errorf("mediaType in manifest should be '%s' not '%s'",
+			MediaTypeManifest, manifest.MediaType)
+	}
+
+	m.Manifest = manifest
+
+	return nil
+}
+
+// MarshalJSON returns the contents of canonical.
+func (m *DeserializedManifest) MarshalJSON() ([]byte, error) {
+	if len(m.canonical) > 0 {
+		return m.canonical, nil
+	}
+	return nil, fmt.Errorf("JSON representation not initialized in DeserializedManifest")
+}
+
+// Payload returns the raw content of the manifest.
+func (m DeserializedManifest) Payload() (string, []byte, error) {
+	return m.MediaType, m.canonical, nil
+}
+
+// bundle is the subset of bundle.json this package cares about.
+type bundle struct {
+	Name    string            `json:"name"`
+	Version string            `json:"version"`
+	Custom  map[string]string `json:"custom,omitempty"`
+}
+
+// bundleArtifact is the artifact.Artifact implementation for CNAB bundles.
+type bundleArtifact struct {
+	manifest Manifest
+	bundle   bundle
+}
+
+func (a bundleArtifact) Kind() artifact.Kind { return KindBundle }
+
+func (a bundleArtifact) Tags() []string {
+	if a.bundle.Version == "" {
+		return nil
+	}
+	return []string{a.bundle.Version}
+}
+
+func (a bundleArtifact) References() []distribution.Descriptor {
+	return a.manifest.referencesList()
+}
+
+func (a bundleArtifact) Extra() map[string]interface{} {
+	return map[string]interface{}{
+		"name":    a.bundle.Name,
+		"version": a.bundle.Version,
+		"custom":  a.bundle.Custom,
+	}
+}
+
+// bundleResolver resolves a CNAB Manifest into a bundleArtifact by fetching
+// and unpacking bundle.json from its Config blob.
+type bundleResolver struct{}
+
+func (bundleResolver) Resolve(ctx context.Context, repo distribution.Repository, m distribution.Manifest) (artifact.Artifact, error) {
+	dm, ok := m.(*DeserializedManifest)
+	if !ok {
+		return nil, fmt.Errorf("cnab: resolver expects *DeserializedManifest, got %T", m)
+	}
+
+	blob, err := repo.Blobs(ctx).Get(ctx, dm.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bundle
+	if err := json.Unmarshal(blob, &b); err != nil {
+		return nil, err
+	}
+
+	return bundleArtifact{manifest: dm.Manifest, bundle: b}, nil
+}
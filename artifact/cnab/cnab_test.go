@@ -0,0 +1,121 @@
+package cnab
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/artifact"
+	"github.com/sequix/distribution/reference"
+)
+
+// fakeBlobService实现distribution.BlobService，只有Get是bundleResolver关心
+// 的路径，其余方法从不被调用。
+type fakeBlobService struct {
+	get func(ctx context.Context, dgst digest.Digest) ([]byte, error)
+}
+
+func (f fakeBlobService) Stat(ctx context.Context, dgst digest.Digest) (distribution.Descriptor, error) {
+	return distribution.Descriptor{}, nil
+}
+
+func (f fakeBlobService) Get(ctx context.Context, dgst digest.Digest) ([]byte, error) {
+	return f.get(ctx, dgst)
+}
+
+func (f fakeBlobService) Put(ctx context.Context, mediaType string, p []byte) (distribution.Descriptor, error) {
+	return distribution.Descriptor{}, nil
+}
+
+func (f fakeBlobService) Writer(ctx context.Context, options ...distribution.BlobWriteOption) (distribution.BlobWriter, error) {
+	return nil, nil
+}
+
+// fakeRepository实现distribution.Repository，只有Blobs是bundleResolver关心
+// 的路径，其余方法从不被调用。
+type fakeRepository struct {
+	blobs distribution.BlobService
+}
+
+func (f fakeRepository) Named() reference.Named { return nil }
+
+func (f fakeRepository) Manifests(ctx context.Context, options ...distribution.ManifestServiceOption) (distribution.ManifestService, error) {
+	return nil, nil
+}
+
+func (f fakeRepository) Blobs(ctx context.Context) distribution.BlobService {
+	return f.blobs
+}
+
+func (f fakeRepository) Tags(ctx context.Context) distribution.TagService { return nil }
+
+func sampleBundleManifest(t *testing.T) (*DeserializedManifest, digest.Digest) {
+	t.Helper()
+
+	bundleJSON := []byte(`{"name":"example","version":"1.2.3"}`)
+	bundleDigest := digest.FromBytes(bundleJSON)
+
+	m := Manifest{
+		Versioned: SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: "application/vnd.cnab.bundle.config.v1+json", Digest: bundleDigest, Size: int64(len(bundleJSON))},
+		References: []distribution.Descriptor{
+			{MediaType: "application/vnd.cnab.invocation-image.v1+json", Digest: "sha256:aaaa", Size: 10},
+		},
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("marshal manifest: %v", err)
+	}
+
+	dm := new(DeserializedManifest)
+	if err := dm.UnmarshalJSON(payload); err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	return dm, bundleDigest
+}
+
+func TestBundleResolverResolve(t *testing.T) {
+	dm, bundleDigest := sampleBundleManifest(t)
+	bundleJSON := []byte(`{"name":"example","version":"1.2.3"}`)
+
+	repo := fakeRepository{
+		blobs: fakeBlobService{
+			get: func(ctx context.Context, dgst digest.Digest) ([]byte, error) {
+				if dgst != bundleDigest {
+					t.Fatalf("Get called with digest %q, want %q", dgst, bundleDigest)
+				}
+				return bundleJSON, nil
+			},
+		},
+	}
+
+	a, err := artifact.Resolve(context.Background(), repo, dm)
+	if err != nil {
+		t.Fatalf("artifact.Resolve: %v", err)
+	}
+
+	if a.Kind() != KindBundle {
+		t.Errorf("Kind() = %q, want %q", a.Kind(), KindBundle)
+	}
+	if got := a.Tags(); len(got) != 1 || got[0] != "1.2.3" {
+		t.Errorf("Tags() = %+v, want [1.2.3]", got)
+	}
+	if len(a.References()) != 2 {
+		t.Errorf("References() = %+v, want config + 1 invocation image", a.References())
+	}
+}
+
+func TestBundleResolverResolveWrongManifestType(t *testing.T) {
+	if _, err := (bundleResolver{}).Resolve(context.Background(), fakeRepository{}, notACnabManifest{}); err == nil {
+		t.Fatal("Resolve should error when given a manifest it doesn't own")
+	}
+}
+
+// notACnabManifest is a minimal distribution.Manifest that isn't a
+// *DeserializedManifest, to exercise bundleResolver's type assertion.
+type notACnabManifest struct{}
+
+func (notACnabManifest) References() []distribution.Descriptor { return nil }
+func (notACnabManifest) Payload() (string, []byte, error)      { return MediaTypeManifest, nil, nil }
@@ -3,6 +3,8 @@ package main
 import (
 	_ "net/http/pprof"
 
+	_ "github.com/sequix/distribution/artifact/cnab"
+	_ "github.com/sequix/distribution/manifest/ocischema"
 	"github.com/sequix/distribution/registry"
 	_ "github.com/sequix/distribution/registry/auth/htpasswd"
 	_ "github.com/sequix/distribution/registry/auth/silly"
@@ -0,0 +1,29 @@
+package distribution
+
+import "github.com/opencontainers/go-digest"
+
+// Descriptor 描述manifest引用的一个对象（layer、config或子manifest）。
+// Descriptor describes targeted content. Used in conjunction with a blob
+// store, a descriptor can be used to fetch, store and target any kind of
+// blob. The struct also describes the wire protocol format. Fields should
+// only be added but never changed.
+type Descriptor struct {
+	// MediaType describe the type of the content. All text based formats are
+	// encoded as utf-8.
+	MediaType string `json:"mediaType,omitempty"`
+
+	// Size in bytes of content.
+	Size int64 `json:"size,omitempty"`
+
+	// Digest uniquely identifies the content. A byte stream can be verified
+	// against this digest.
+	Digest digest.Digest `json:"digest,omitempty"`
+
+	// URLs 是该对象可被直接获取的外部地址，比如nbd-url；非distributable的layer必须携带。
+	// URLs contains the source URLs of this content.
+	URLs []string `json:"urls,omitempty"`
+
+	// Annotations 是该对象的任意附加KV信息，不影响对象本身的内容和摘要计算。
+	// Annotations contains arbitrary metadata relating to the targeted content.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
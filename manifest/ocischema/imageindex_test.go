@@ -0,0 +1,38 @@
+package ocischema
+
+import (
+	"testing"
+
+	"github.com/sequix/distribution"
+)
+
+func TestDeserializedImageIndexUnmarshalJSONRejectsWrongMediaType(t *testing.T) {
+	ii, err := IndexFromStruct(ImageIndex{
+		Versioned: IndexSchemaVersion,
+		Manifests: []ManifestDescriptor{
+			{
+				Descriptor: distribution.Descriptor{MediaType: MediaTypeImageManifest, Digest: "sha256:amd64"},
+				Platform:   PlatformSpec{OS: "linux", Architecture: "amd64"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("IndexFromStruct: %v", err)
+	}
+
+	_, payload, err := ii.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+
+	var roundTripped DeserializedImageIndex
+	if err := roundTripped.UnmarshalJSON(payload); err != nil {
+		t.Fatalf("UnmarshalJSON should accept an index with the correct media type: %v", err)
+	}
+
+	wrongType := []byte(`{"schemaVersion":1,"mediaType":"application/vnd.sequix.piddle.index.v1+json","manifests":[]}`)
+	var rejected DeserializedImageIndex
+	if err := rejected.UnmarshalJSON(wrongType); err == nil {
+		t.Fatal("UnmarshalJSON should reject an index whose mediaType isn't MediaTypeImageIndex")
+	}
+}
@@ -0,0 +1,55 @@
+package ocischema
+
+import (
+	"testing"
+
+	"github.com/sequix/distribution"
+)
+
+func TestDeserializedManifestUnmarshalJSONRejectsWrongMediaType(t *testing.T) {
+	m, err := FromStruct(Manifest{
+		Versioned: SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: MediaTypeImageConfig, Digest: "sha256:aaaa", Size: 2},
+		Layers: []distribution.Descriptor{
+			{MediaType: MediaTypeImageLayerGzip, Digest: "sha256:bbbb", Size: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FromStruct: %v", err)
+	}
+
+	_, payload, err := m.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+
+	var roundTripped DeserializedManifest
+	if err := roundTripped.UnmarshalJSON(payload); err != nil {
+		t.Fatalf("UnmarshalJSON should accept a manifest with the correct media type: %v", err)
+	}
+
+	wrongType := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v2+json","config":{},"layers":[]}`)
+	var rejected DeserializedManifest
+	if err := rejected.UnmarshalJSON(wrongType); err == nil {
+		t.Fatal("UnmarshalJSON should reject a manifest whose mediaType isn't MediaTypeImageManifest")
+	}
+}
+
+func TestManifestReferencesIncludesConfigAndLayers(t *testing.T) {
+	m := Manifest{
+		Versioned: SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: MediaTypeImageConfig, Digest: "sha256:aaaa", Size: 2},
+		Layers: []distribution.Descriptor{
+			{MediaType: MediaTypeImageLayerGzip, Digest: "sha256:bbbb", Size: 10},
+			{MediaType: MediaTypeImageLayerZstd, Digest: "sha256:cccc", Size: 20},
+		},
+	}
+
+	refs := m.References()
+	if len(refs) != 3 {
+		t.Fatalf("References() = %+v, want config + 2 layers", refs)
+	}
+	if refs[0].Digest != m.Config.Digest {
+		t.Errorf("References()[0] = %+v, want the config descriptor first", refs[0])
+	}
+}
@@ -0,0 +1,144 @@
+package piddle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/sequix/distribution"
+)
+
+// isLayerMediaType 判断mediaType是否为piddle package所支持的layer类型
+func isLayerMediaType(mediaType string) bool {
+	switch mediaType {
+	case MediaTypeLayer, MediaTypeLayerGzip, MediaTypeLayerZstd,
+		MediaTypeNondistributableLayer, MediaTypeNondistributableLayerGzip, MediaTypeNondistributableLayerZstd:
+		return true
+	default:
+		return false
+	}
+}
+
+// manifestBuilder is used to build Manifest manifests.
+type manifestBuilder struct {
+	// bs is a BlobService used to publish the configuration blob.
+	bs distribution.BlobService
+
+	// configJSON references the serialized image configuration.
+	configJSON []byte
+
+	// layers is a list of layer descriptors that have been added to this builder.
+	layers []distribution.Descriptor
+}
+
+// NewManifestBuilder is used to build new Manifest manifests for the piddle
+// package. It takes a BlobService so it can publish the configuration blob
+// as part of the Build process.
+func NewManifestBuilder(bs distribution.BlobService, configJSON []byte) distribution.ManifestBuilder {
+	return &manifestBuilder{
+		bs:         bs,
+		configJSON: configJSON,
+	}
+}
+
+// Build 把configJSON上传到blob store，并把已添加的layers一起打包成Manifest
+func (mb *manifestBuilder) Build(ctx context.Context) (distribution.Manifest, error) {
+	m := Manifest{
+		Versioned: SchemaVersion,
+		Layers:    make([]distribution.Descriptor, len(mb.layers)),
+	}
+	copy(m.Layers, mb.layers)
+
+	configDigest := digest.FromBytes(mb.configJSON)
+
+	var err error
+	m.Config, err = mb.bs.Stat(ctx, configDigest)
+	switch err {
+	case nil:
+		// nop, config blob已存在，走下面的公共返回路径
+	case distribution.ErrBlobUnknown:
+		m.Config, err = mb.bs.Put(ctx, MediaTypeImageConfig, mb.configJSON)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	// Stat和Put都不保证返回的descriptor带上我们期望的MediaType（Put总是把它设为
+	// application/octet-stream），这里统一重新赋值
+	m.Config.MediaType = MediaTypeImageConfig
+
+	// 和convert.go的FromOCI一样，在FromStruct之前校验，避免产出一个piddle自己的
+	// UnmarshalJSON无法再解析回来的manifest。
+	if err := validateLayerURLs(m.Layers); err != nil {
+		return nil, err
+	}
+
+	return FromStruct(m)
+}
+
+// AppendReference 添加一个layer，要求其MediaType是piddle package所支持的layer类型，
+// 且其URLs满足validateLayerURLs的要求（非distributable必须带URL，反之不得带）
+func (mb *manifestBuilder) AppendReference(d distribution.Describable) error {
+	descriptor := d.Descriptor()
+	if !isLayerMediaType(descriptor.MediaType) {
+		return fmt.Errorf("piddle: unsupported layer media type: %s", descriptor.MediaType)
+	}
+	if err := validateLayerURLs([]distribution.Descriptor{descriptor}); err != nil {
+		return err
+	}
+	mb.layers = append(mb.layers, descriptor)
+	return nil
+}
+
+// References 按添加顺序（base到head）返回已添加的layers
+func (mb *manifestBuilder) References() []distribution.Descriptor {
+	return mb.layers
+}
+
+// ImageIndexBuilder is used to build ImageIndex manifests, each entry
+// referencing a child image manifest along with the platform it was built
+// for. Unlike manifestBuilder, it does not implement distribution.ManifestBuilder
+// since a plain Describable cannot carry the per-manifest Platform metadata.
+type ImageIndexBuilder struct {
+	// manifests is a list of manifest descriptors that have been added to this builder.
+	manifests []ManifestDescriptor
+}
+
+// NewImageIndexBuilder is used to build new ImageIndex manifests.
+func NewImageIndexBuilder() *ImageIndexBuilder {
+	return &ImageIndexBuilder{}
+}
+
+// Build 把已添加的child manifest descriptors打包成ImageIndex
+func (mb *ImageIndexBuilder) Build(ctx context.Context) (distribution.Manifest, error) {
+	return FromDescriptors(mb.manifests)
+}
+
+// AppendManifest 添加一个child manifest及其运行的platform
+func (mb *ImageIndexBuilder) AppendManifest(m distribution.Manifest, platform PlatformSpec) error {
+	mediaType, payload, err := m.Payload()
+	if err != nil {
+		return err
+	}
+
+	mb.manifests = append(mb.manifests, ManifestDescriptor{
+		Descriptor: distribution.Descriptor{
+			MediaType: mediaType,
+			Digest:    digest.FromBytes(payload),
+			Size:      int64(len(payload)),
+		},
+		Platform: platform,
+	})
+	return nil
+}
+
+// References 按添加顺序返回已添加的child manifest descriptors
+func (mb *ImageIndexBuilder) References() []distribution.Descriptor {
+	references := make([]distribution.Descriptor, len(mb.manifests))
+	for i, m := range mb.manifests {
+		references[i] = m.Descriptor
+	}
+	return references
+}
@@ -0,0 +1,201 @@
+package piddle
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/sequix/distribution"
+)
+
+// fakeBlobService实现distribution.BlobService，只有Stat/Put是测试关心的路径，
+// 其余方法从不被manifestBuilder调用，返回错误即可。
+type fakeBlobService struct {
+	stat func(ctx context.Context, dgst digest.Digest) (distribution.Descriptor, error)
+	put  func(ctx context.Context, mediaType string, p []byte) (distribution.Descriptor, error)
+}
+
+func (f fakeBlobService) Stat(ctx context.Context, dgst digest.Digest) (distribution.Descriptor, error) {
+	return f.stat(ctx, dgst)
+}
+
+func (f fakeBlobService) Get(ctx context.Context, dgst digest.Digest) ([]byte, error) {
+	return nil, fmt.Errorf("fakeBlobService: Get not implemented")
+}
+
+func (f fakeBlobService) Put(ctx context.Context, mediaType string, p []byte) (distribution.Descriptor, error) {
+	return f.put(ctx, mediaType, p)
+}
+
+func (f fakeBlobService) Writer(ctx context.Context, options ...distribution.BlobWriteOption) (distribution.BlobWriter, error) {
+	return nil, fmt.Errorf("fakeBlobService: Writer not implemented")
+}
+
+func TestManifestBuilderBuildSetsConfigMediaType(t *testing.T) {
+	configJSON := []byte(`{"architecture":"amd64","os":"linux"}`)
+	configDigest := digest.FromBytes(configJSON)
+
+	// 两种路径返回的descriptor都带着真实的digest和size，但MediaType设成
+	// application/octet-stream，模拟blob store不记录上传者预期媒体类型的真实行为。
+	tests := []struct {
+		name string
+		bs   distribution.BlobService
+	}{
+		{
+			name: "config blob already exists (Stat hits)",
+			bs: fakeBlobService{
+				stat: func(ctx context.Context, dgst digest.Digest) (distribution.Descriptor, error) {
+					return distribution.Descriptor{
+						Digest:    dgst,
+						Size:      int64(len(configJSON)),
+						MediaType: "application/octet-stream",
+					}, nil
+				},
+				put: func(ctx context.Context, mediaType string, p []byte) (distribution.Descriptor, error) {
+					t.Fatal("Put should not be called when Stat already finds the blob")
+					return distribution.Descriptor{}, nil
+				},
+			},
+		},
+		{
+			name: "config blob is new (Stat misses, Put uploads)",
+			bs: fakeBlobService{
+				stat: func(ctx context.Context, dgst digest.Digest) (distribution.Descriptor, error) {
+					return distribution.Descriptor{}, distribution.ErrBlobUnknown
+				},
+				put: func(ctx context.Context, mediaType string, p []byte) (distribution.Descriptor, error) {
+					return distribution.Descriptor{
+						Digest:    digest.FromBytes(p),
+						Size:      int64(len(p)),
+						MediaType: "application/octet-stream",
+					}, nil
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewManifestBuilder(tc.bs, configJSON)
+
+			m, err := b.Build(context.Background())
+			if err != nil {
+				t.Fatalf("Build returned error: %v", err)
+			}
+
+			pm, ok := m.(*DeserializedManifest)
+			if !ok {
+				t.Fatalf("Build returned %T, want *DeserializedManifest", m)
+			}
+
+			if pm.Config.MediaType != MediaTypeImageConfig {
+				t.Errorf("Config.MediaType = %q, want %q", pm.Config.MediaType, MediaTypeImageConfig)
+			}
+			if pm.Config.Digest != configDigest {
+				t.Errorf("Config.Digest = %q, want %q", pm.Config.Digest, configDigest)
+			}
+		})
+	}
+}
+
+func TestManifestBuilderAppendReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaType string
+		wantErr   bool
+	}{
+		{"plain tar layer", MediaTypeLayer, false},
+		{"gzip layer", MediaTypeLayerGzip, false},
+		{"zstd layer", MediaTypeLayerZstd, false},
+		{"nondistributable layer without URLs is rejected", MediaTypeNondistributableLayer, true},
+		{"config media type is not a layer", MediaTypeImageConfig, true},
+		{"unrelated media type", "application/vnd.oci.image.layer.v1.tar", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewManifestBuilder(nil, nil)
+			err := b.AppendReference(describable{distribution.Descriptor{MediaType: tc.mediaType}})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("AppendReference(%q) error = %v, wantErr %v", tc.mediaType, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			refs := b.References()
+			if len(refs) != 1 || refs[0].MediaType != tc.mediaType {
+				t.Errorf("References() = %+v, want single descriptor with MediaType %q", refs, tc.mediaType)
+			}
+		})
+	}
+}
+
+func TestManifestBuilderAppendReferenceNondistributableWithURL(t *testing.T) {
+	b := NewManifestBuilder(nil, nil)
+	d := describable{distribution.Descriptor{
+		MediaType: MediaTypeNondistributableLayer,
+		URLs:      []string{"nbd://host/vol"},
+	}}
+	if err := b.AppendReference(d); err != nil {
+		t.Fatalf("AppendReference with a URL: %v", err)
+	}
+	refs := b.References()
+	if len(refs) != 1 || refs[0].MediaType != MediaTypeNondistributableLayer {
+		t.Errorf("References() = %+v, want single nondistributable descriptor", refs)
+	}
+}
+
+func TestImageIndexBuilderAppendManifestAndBuild(t *testing.T) {
+	b := NewImageIndexBuilder()
+
+	amd64, err := FromStruct(Manifest{
+		Versioned: SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: MediaTypeImageConfig, Digest: "sha256:aaaa", Size: 2},
+	})
+	if err != nil {
+		t.Fatalf("FromStruct: %v", err)
+	}
+	_, amd64Payload, err := amd64.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+	amd64Digest := digest.FromBytes(amd64Payload)
+
+	if err := b.AppendManifest(amd64, PlatformSpec{OS: "linux", Architecture: "amd64"}); err != nil {
+		t.Fatalf("AppendManifest: %v", err)
+	}
+
+	refs := b.References()
+	if len(refs) != 1 || refs[0].Digest != amd64Digest || refs[0].MediaType != MediaTypeImageManifest {
+		t.Fatalf("References() = %+v, want a single %q descriptor for %q", refs, MediaTypeImageManifest, amd64Digest)
+	}
+
+	built, err := b.Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	index, ok := built.(*DeserializedImageIndex)
+	if !ok {
+		t.Fatalf("Build returned %T, want *DeserializedImageIndex", built)
+	}
+	if len(index.Manifests) != 1 {
+		t.Fatalf("Manifests = %+v, want a single entry", index.Manifests)
+	}
+	if index.Manifests[0].Digest != amd64Digest {
+		t.Errorf("Manifests[0].Digest = %q, want %q", index.Manifests[0].Digest, amd64Digest)
+	}
+	if index.Manifests[0].Platform.OS != "linux" || index.Manifests[0].Platform.Architecture != "amd64" {
+		t.Errorf("Manifests[0].Platform = %+v, want linux/amd64", index.Manifests[0].Platform)
+	}
+}
+
+// describable是测试用的最小distribution.Describable实现。
+type describable struct {
+	descriptor distribution.Descriptor
+}
+
+func (d describable) Descriptor() distribution.Descriptor {
+	return d.descriptor
+}
@@ -0,0 +1,178 @@
+package piddle
+
+import (
+	"fmt"
+
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/manifest/ocischema"
+)
+
+// ociToPiddleMediaType 和 piddleToOCIMediaType 在OCI和piddle各自的config/layer
+// 媒体类型之间做映射，使同一份blob可以被两种格式的manifest同时引用。
+var ociToPiddleMediaType = map[string]string{
+	ocischema.MediaTypeImageConfig:                    MediaTypeImageConfig,
+	ocischema.MediaTypeImageLayer:                     MediaTypeLayer,
+	ocischema.MediaTypeImageLayerGzip:                 MediaTypeLayerGzip,
+	ocischema.MediaTypeImageLayerZstd:                 MediaTypeLayerZstd,
+	ocischema.MediaTypeImageLayerNonDistributable:     MediaTypeNondistributableLayer,
+	ocischema.MediaTypeImageLayerNonDistributableGzip: MediaTypeNondistributableLayerGzip,
+	ocischema.MediaTypeImageLayerNonDistributableZstd: MediaTypeNondistributableLayerZstd,
+}
+
+var piddleToOCIMediaType = func() map[string]string {
+	m := make(map[string]string, len(ociToPiddleMediaType))
+	for oci, pid := range ociToPiddleMediaType {
+		m[pid] = oci
+	}
+	return m
+}()
+
+// FromOCI 把一个OCI image manifest转换为piddle的DeserializedManifest，
+// 使OCI客户端推送的镜像也能被piddle-aware客户端拉取。
+func FromOCI(m *ocischema.DeserializedManifest) (*DeserializedManifest, error) {
+	config, err := mapDescriptorMediaType(m.Config, ociToPiddleMediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make([]distribution.Descriptor, len(m.Layers))
+	for i, l := range m.Layers {
+		layers[i], err = mapDescriptorMediaType(l, ociToPiddleMediaType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// OCI不像piddle一样强制非distributable layer必须带URLs，
+	// 在这里提前校验，避免产出一个piddle自己的UnmarshalJSON无法再解析回来的manifest。
+	if err := validateLayerURLs(layers); err != nil {
+		return nil, err
+	}
+
+	return FromStruct(Manifest{
+		Versioned:   SchemaVersion,
+		Config:      config,
+		Layers:      layers,
+		Annotations: m.Annotations,
+	})
+}
+
+// ToOCI 把一个piddle image manifest转换为OCI的DeserializedManifest，
+// 使piddle-native的镜像也能被标准OCI客户端拉取。
+func ToOCI(m *DeserializedManifest) (*ocischema.DeserializedManifest, error) {
+	config, err := mapDescriptorMediaType(m.Config, piddleToOCIMediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make([]distribution.Descriptor, len(m.Layers))
+	for i, l := range m.Layers {
+		layers[i], err = mapDescriptorMediaType(l, piddleToOCIMediaType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ocischema.FromStruct(ocischema.Manifest{
+		Versioned:   ocischema.SchemaVersion,
+		Config:      config,
+		Layers:      layers,
+		Annotations: m.Annotations,
+	})
+}
+
+// mapDescriptorMediaType 用mapping把descriptor的MediaType替换为对应格式的等价类型，
+// 其余字段（digest、size、urls、annotations）原样保留。
+func mapDescriptorMediaType(d distribution.Descriptor, mapping map[string]string) (distribution.Descriptor, error) {
+	mapped, ok := mapping[d.MediaType]
+	if !ok {
+		return distribution.Descriptor{}, fmt.Errorf("piddle: no equivalent media type for %q", d.MediaType)
+	}
+	d.MediaType = mapped
+	return d, nil
+}
+
+// FromOCIIndex 把一个OCI image index转换为piddle的DeserializedImageIndex。
+func FromOCIIndex(ii *ocischema.DeserializedImageIndex) (*DeserializedImageIndex, error) {
+	manifests := make([]ManifestDescriptor, len(ii.Manifests))
+	for i, m := range ii.Manifests {
+		manifests[i] = ManifestDescriptor{
+			Descriptor: distribution.Descriptor{
+				MediaType:   MediaTypeImageManifest,
+				Digest:      m.Digest,
+				Size:        m.Size,
+				URLs:        m.URLs,
+				Annotations: m.Annotations,
+			},
+			Platform: PlatformSpec(m.Platform),
+		}
+	}
+
+	return IndexFromStruct(ImageIndex{
+		Versioned:   IndexSchemaVersion,
+		Manifests:   manifests,
+		Annotations: ii.Annotations,
+	})
+}
+
+// ToOCIIndex 把一个piddle ImageIndex转换为OCI的DeserializedImageIndex。
+func ToOCIIndex(ii *DeserializedImageIndex) (*ocischema.DeserializedImageIndex, error) {
+	manifests := make([]ocischema.ManifestDescriptor, len(ii.Manifests))
+	for i, m := range ii.Manifests {
+		manifests[i] = ocischema.ManifestDescriptor{
+			Descriptor: distribution.Descriptor{
+				MediaType:   ocischema.MediaTypeImageManifest,
+				Digest:      m.Digest,
+				Size:        m.Size,
+				URLs:        m.URLs,
+				Annotations: m.Annotations,
+			},
+			Platform: ocischema.PlatformSpec(m.Platform),
+		}
+	}
+
+	return ocischema.IndexFromStruct(ocischema.ImageIndex{
+		Versioned:   ocischema.IndexSchemaVersion,
+		Manifests:   manifests,
+		Annotations: ii.Annotations,
+	})
+}
+
+// 把piddle<->OCI的转换函数注册为distribution.ManifestConverter，使manifest
+// handler可以在客户端Accept的类型与存储的类型不一致时透明地做转换。
+func init() {
+	register := func(from, to string, fn distribution.ManifestConverter) {
+		if err := distribution.RegisterManifestConverter(from, to, fn); err != nil {
+			panic(fmt.Sprintf("Unable to register manifest converter: %s", err))
+		}
+	}
+
+	register(ocischema.MediaTypeImageManifest, MediaTypeImageManifest, func(m distribution.Manifest) (distribution.Manifest, error) {
+		om, ok := m.(*ocischema.DeserializedManifest)
+		if !ok {
+			return nil, fmt.Errorf("piddle: expected *ocischema.DeserializedManifest, got %T", m)
+		}
+		return FromOCI(om)
+	})
+	register(MediaTypeImageManifest, ocischema.MediaTypeImageManifest, func(m distribution.Manifest) (distribution.Manifest, error) {
+		pm, ok := m.(*DeserializedManifest)
+		if !ok {
+			return nil, fmt.Errorf("piddle: expected *piddle.DeserializedManifest, got %T", m)
+		}
+		return ToOCI(pm)
+	})
+	register(ocischema.MediaTypeImageIndex, MediaTypeImageIndex, func(m distribution.Manifest) (distribution.Manifest, error) {
+		oi, ok := m.(*ocischema.DeserializedImageIndex)
+		if !ok {
+			return nil, fmt.Errorf("piddle: expected *ocischema.DeserializedImageIndex, got %T", m)
+		}
+		return FromOCIIndex(oi)
+	})
+	register(MediaTypeImageIndex, ocischema.MediaTypeImageIndex, func(m distribution.Manifest) (distribution.Manifest, error) {
+		pi, ok := m.(*DeserializedImageIndex)
+		if !ok {
+			return nil, fmt.Errorf("piddle: expected *piddle.DeserializedImageIndex, got %T", m)
+		}
+		return ToOCIIndex(pi)
+	})
+}
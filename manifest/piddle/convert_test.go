@@ -0,0 +1,157 @@
+package piddle
+
+import (
+	"testing"
+
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/manifest/ocischema"
+)
+
+func TestFromOCI(t *testing.T) {
+	tests := []struct {
+		name    string
+		layers  []distribution.Descriptor
+		wantErr bool
+	}{
+		{
+			name: "distributable layer without URLs converts cleanly",
+			layers: []distribution.Descriptor{
+				{MediaType: ocischema.MediaTypeImageLayerGzip, Digest: "sha256:aaaa", Size: 10},
+			},
+		},
+		{
+			name: "non-distributable layer without URLs is rejected",
+			layers: []distribution.Descriptor{
+				{MediaType: ocischema.MediaTypeImageLayerNonDistributable, Digest: "sha256:bbbb", Size: 10},
+			},
+			wantErr: true,
+		},
+		{
+			name: "distributable layer with foreign URLs is rejected",
+			layers: []distribution.Descriptor{
+				{MediaType: ocischema.MediaTypeImageLayerGzip, Digest: "sha256:cccc", Size: 10, URLs: []string{"https://example.com/layer"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "non-distributable layer with URLs converts cleanly",
+			layers: []distribution.Descriptor{
+				{MediaType: ocischema.MediaTypeImageLayerNonDistributable, Digest: "sha256:dddd", Size: 10, URLs: []string{"nbd://host/vol"}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			om, err := ocischema.FromStruct(ocischema.Manifest{
+				Versioned: ocischema.SchemaVersion,
+				Config:    distribution.Descriptor{MediaType: ocischema.MediaTypeImageConfig, Digest: "sha256:eeee", Size: 2},
+				Layers:    tc.layers,
+			})
+			if err != nil {
+				t.Fatalf("ocischema.FromStruct: %v", err)
+			}
+
+			pm, err := FromOCI(om)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("FromOCI() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			// A converted manifest must round-trip through piddle's own
+			// UnmarshalJSON, since that's the path the registry's store/fetch
+			// cycle exercises once the manifest is written back out.
+			_, payload, err := pm.Payload()
+			if err != nil {
+				t.Fatalf("Payload: %v", err)
+			}
+			var roundTripped DeserializedManifest
+			if err := roundTripped.UnmarshalJSON(payload); err != nil {
+				t.Fatalf("converted manifest does not round-trip through UnmarshalJSON: %v", err)
+			}
+		})
+	}
+}
+
+func TestToOCI(t *testing.T) {
+	pm, err := FromStruct(Manifest{
+		Versioned: SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: MediaTypeImageConfig, Digest: "sha256:eeee", Size: 2},
+		Layers: []distribution.Descriptor{
+			{MediaType: MediaTypeLayerGzip, Digest: "sha256:aaaa", Size: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FromStruct: %v", err)
+	}
+
+	om, err := ToOCI(pm)
+	if err != nil {
+		t.Fatalf("ToOCI: %v", err)
+	}
+	if om.Config.MediaType != ocischema.MediaTypeImageConfig {
+		t.Errorf("Config.MediaType = %q, want %q", om.Config.MediaType, ocischema.MediaTypeImageConfig)
+	}
+	if len(om.Layers) != 1 || om.Layers[0].MediaType != ocischema.MediaTypeImageLayerGzip {
+		t.Errorf("Layers = %+v, want a single %q layer", om.Layers, ocischema.MediaTypeImageLayerGzip)
+	}
+}
+
+func TestFromOCIIndexPreservesAnnotations(t *testing.T) {
+	oi, err := ocischema.FromDescriptors([]ocischema.ManifestDescriptor{
+		{
+			Descriptor: distribution.Descriptor{MediaType: ocischema.MediaTypeImageManifest, Digest: "sha256:amd64", Size: 4},
+			Platform:   ocischema.PlatformSpec{OS: "linux", Architecture: "amd64"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ocischema.FromDescriptors: %v", err)
+	}
+	oi.Annotations = map[string]string{"com.example.release": "1.0"}
+	oi, err = ocischema.IndexFromStruct(oi.ImageIndex)
+	if err != nil {
+		t.Fatalf("ocischema.IndexFromStruct: %v", err)
+	}
+
+	pi, err := FromOCIIndex(oi)
+	if err != nil {
+		t.Fatalf("FromOCIIndex: %v", err)
+	}
+
+	if pi.Annotations["com.example.release"] != "1.0" {
+		t.Errorf("Annotations = %+v, want com.example.release=1.0 preserved", pi.Annotations)
+	}
+	if len(pi.Manifests) != 1 || pi.Manifests[0].MediaType != MediaTypeImageManifest {
+		t.Errorf("Manifests = %+v, want a single %q entry", pi.Manifests, MediaTypeImageManifest)
+	}
+}
+
+func TestToOCIIndexPreservesAnnotations(t *testing.T) {
+	pi, err := IndexFromStruct(ImageIndex{
+		Versioned: IndexSchemaVersion,
+		Manifests: []ManifestDescriptor{
+			{
+				Descriptor: distribution.Descriptor{MediaType: MediaTypeImageManifest, Digest: "sha256:amd64", Size: 4},
+				Platform:   PlatformSpec{OS: "linux", Architecture: "amd64"},
+			},
+		},
+		Annotations: map[string]string{"com.example.release": "1.0"},
+	})
+	if err != nil {
+		t.Fatalf("IndexFromStruct: %v", err)
+	}
+
+	oi, err := ToOCIIndex(pi)
+	if err != nil {
+		t.Fatalf("ToOCIIndex: %v", err)
+	}
+
+	if oi.Annotations["com.example.release"] != "1.0" {
+		t.Errorf("Annotations = %+v, want com.example.release=1.0 preserved", oi.Annotations)
+	}
+	if len(oi.Manifests) != 1 || oi.Manifests[0].MediaType != ocischema.MediaTypeImageManifest {
+		t.Errorf("Manifests = %+v, want a single %q entry", oi.Manifests, ocischema.MediaTypeImageManifest)
+	}
+}
@@ -0,0 +1,163 @@
+package piddle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/manifest"
+)
+
+var (
+	// IndexSchemaVersion provides a pre-initialized version structure for
+	// this package's version of the image index.
+	IndexSchemaVersion = manifest.Versioned{
+		SchemaVersion: 1,
+		MediaType:     MediaTypeImageIndex,
+	}
+)
+
+// PlatformSpec 描述 ManifestDescriptor 所指向的 image manifest 运行的平台。
+// PlatformSpec describes a platform which a particular manifest is specific to.
+type PlatformSpec struct {
+	// Architecture field specifies the CPU architecture, for example
+	// `amd64` or `ppc64le`.
+	Architecture string `json:"architecture"`
+
+	// OS specifies the operating system, for example `linux` or `windows`.
+	OS string `json:"os"`
+
+	// OSVersion is an optional field specifying the operating system
+	// version, for example `10.0.14393.1066` on `windows`.
+	OSVersion string `json:"os.version,omitempty"`
+
+	// OSFeatures is an optional field specifying an array of strings,
+	// each listing a required OS feature.
+	OSFeatures []string `json:"os.features,omitempty"`
+
+	// Variant is an optional field specifying a variant of the CPU, for
+	// example `v7` to specify ARMv7 when architecture is `arm`.
+	Variant string `json:"variant,omitempty"`
+}
+
+// ManifestDescriptor 在 distribution.Descriptor 的基础上附加了该 manifest 对应的平台信息，
+// 用于 image index 中挑选与请求平台匹配的 image manifest。
+// ManifestDescriptor references a platform-specific manifest.
+type ManifestDescriptor struct {
+	distribution.Descriptor
+
+	// Platform describes the platform which the image in the manifest runs on.
+	Platform PlatformSpec `json:"platform"`
+}
+
+// ImageIndex defines a piddle image index, referencing manifests for
+// several platforms.
+type ImageIndex struct {
+	manifest.Versioned
+
+	// Manifests references the manifests for the different platforms.
+	Manifests []ManifestDescriptor `json:"manifests"`
+
+	// Annotations 是该image index的任意附加KV信息。
+	// Annotations contains arbitrary metadata for the image index.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// References returns the distribution descriptors of the image manifests
+// referenced by this index.
+func (ii ImageIndex) References() []distribution.Descriptor {
+	references := make([]distribution.Descriptor, len(ii.Manifests))
+	for i, m := range ii.Manifests {
+		references[i] = m.Descriptor
+	}
+	return references
+}
+
+// Select returns the ManifestDescriptor of the first manifest matching the
+// given os/architecture/variant, mirroring the platform-matching rules used
+// by manifestlist upstream. ok is false when no manifest matches.
+func (ii ImageIndex) Select(os, architecture, variant string) (md ManifestDescriptor, ok bool) {
+	for _, m := range ii.Manifests {
+		if m.Platform.OS != os || m.Platform.Architecture != architecture {
+			continue
+		}
+		if variant != "" && m.Platform.Variant != variant {
+			continue
+		}
+		return m, true
+	}
+	return ManifestDescriptor{}, false
+}
+
+// DeserializedImageIndex wraps ImageIndex with a copy of the original JSON.
+// It satisfies the distribution.Manifest interface.
+type DeserializedImageIndex struct {
+	ImageIndex
+
+	// canonical is the canonical byte representation of the ImageIndex.
+	canonical []byte
+}
+
+// FromDescriptors takes a slice of ManifestDescriptors, and returns a
+// DeserializedImageIndex which contains the resulting index and its JSON representation.
+func FromDescriptors(descriptors []ManifestDescriptor) (*DeserializedImageIndex, error) {
+	m := ImageIndex{
+		Versioned: IndexSchemaVersion,
+		Manifests: make([]ManifestDescriptor, len(descriptors)),
+	}
+	copy(m.Manifests, descriptors)
+
+	return IndexFromStruct(m)
+}
+
+// IndexFromStruct takes an ImageIndex structure, marshals it to JSON, and
+// returns a DeserializedImageIndex which contains the index and its JSON
+// representation. Unlike FromDescriptors it lets the caller set index-level
+// fields (e.g. Annotations) that aren't derivable from the manifest list alone.
+func IndexFromStruct(ii ImageIndex) (*DeserializedImageIndex, error) {
+	var deserialized DeserializedImageIndex
+	deserialized.ImageIndex = ii
+
+	var err error
+	deserialized.canonical, err = json.MarshalIndent(&ii, "", "   ")
+	return &deserialized, err
+}
+
+// UnmarshalJSON populates a new ImageIndex struct from JSON data.
+func (ii *DeserializedImageIndex) UnmarshalJSON(b []byte) error {
+	ii.canonical = make([]byte, len(b))
+	// store manifest in canonical
+	copy(ii.canonical, b)
+
+	// Unmarshal canonical JSON into ImageIndex object
+	var index ImageIndex
+	if err := json.Unmarshal(ii.canonical, &index); err != nil {
+		return err
+	}
+
+	if index.MediaType != MediaTypeImageIndex {
+		return fmt.Errorf("mediaType in image index should be '%s' not '%s'",
+			MediaTypeImageIndex, index.MediaType)
+	}
+
+	ii.ImageIndex = index
+
+	return nil
+}
+
+// MarshalJSON returns the contents of canonical. If canonical is empty, it
+// returns an error.
+func (ii *DeserializedImageIndex) MarshalJSON() ([]byte, error) {
+	if len(ii.canonical) > 0 {
+		return ii.canonical, nil
+	}
+
+	return nil, errors.New("JSON representation not initialized in DeserializedImageIndex")
+}
+
+// Payload returns the raw content of the image index. The contents can be
+// used to calculate the content identifier.
+func (ii DeserializedImageIndex) Payload() (string, []byte, error) {
+	return ii.MediaType, ii.canonical, nil
+}
@@ -0,0 +1,129 @@
+package piddle
+
+import (
+	"testing"
+
+	"github.com/sequix/distribution"
+)
+
+func TestImageIndexSelect(t *testing.T) {
+	index := ImageIndex{
+		Versioned: IndexSchemaVersion,
+		Manifests: []ManifestDescriptor{
+			{
+				Descriptor: distribution.Descriptor{MediaType: MediaTypeImageManifest, Digest: "sha256:amd64"},
+				Platform:   PlatformSpec{OS: "linux", Architecture: "amd64"},
+			},
+			{
+				Descriptor: distribution.Descriptor{MediaType: MediaTypeImageManifest, Digest: "sha256:arm64"},
+				Platform:   PlatformSpec{OS: "linux", Architecture: "arm64"},
+			},
+			{
+				Descriptor: distribution.Descriptor{MediaType: MediaTypeImageManifest, Digest: "sha256:armv7"},
+				Platform:   PlatformSpec{OS: "linux", Architecture: "arm", Variant: "v7"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		os           string
+		architecture string
+		variant      string
+		wantDigest   string
+		wantOK       bool
+	}{
+		{
+			name: "matches os and architecture", os: "linux", architecture: "amd64",
+			wantDigest: "sha256:amd64", wantOK: true,
+		},
+		{
+			name: "matches a different architecture", os: "linux", architecture: "arm64",
+			wantDigest: "sha256:arm64", wantOK: true,
+		},
+		{
+			name: "variant is ignored when not requested", os: "linux", architecture: "arm",
+			wantDigest: "sha256:armv7", wantOK: true,
+		},
+		{
+			name: "variant must match when requested", os: "linux", architecture: "arm", variant: "v7",
+			wantDigest: "sha256:armv7", wantOK: true,
+		},
+		{
+			name: "mismatched variant does not match", os: "linux", architecture: "arm", variant: "v6",
+			wantOK: false,
+		},
+		{
+			name: "unknown os does not match", os: "windows", architecture: "amd64",
+			wantOK: false,
+		},
+		{
+			name: "unknown architecture does not match", os: "linux", architecture: "riscv64",
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			md, ok := index.Select(tc.os, tc.architecture, tc.variant)
+			if ok != tc.wantOK {
+				t.Fatalf("Select(%q, %q, %q) ok = %v, want %v", tc.os, tc.architecture, tc.variant, ok, tc.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if string(md.Digest) != tc.wantDigest {
+				t.Errorf("Select(%q, %q, %q) digest = %q, want %q", tc.os, tc.architecture, tc.variant, md.Digest, tc.wantDigest)
+			}
+		})
+	}
+}
+
+func TestImageIndexReferences(t *testing.T) {
+	index := ImageIndex{
+		Versioned: IndexSchemaVersion,
+		Manifests: []ManifestDescriptor{
+			{Descriptor: distribution.Descriptor{MediaType: MediaTypeImageManifest, Digest: "sha256:amd64"}, Platform: PlatformSpec{OS: "linux", Architecture: "amd64"}},
+			{Descriptor: distribution.Descriptor{MediaType: MediaTypeImageManifest, Digest: "sha256:arm64"}, Platform: PlatformSpec{OS: "linux", Architecture: "arm64"}},
+		},
+	}
+
+	refs := index.References()
+	if len(refs) != 2 {
+		t.Fatalf("References() = %+v, want 2 entries", refs)
+	}
+	if refs[0].Digest != "sha256:amd64" || refs[1].Digest != "sha256:arm64" {
+		t.Errorf("References() = %+v, want order preserved", refs)
+	}
+}
+
+func TestDeserializedImageIndexUnmarshalJSONRejectsWrongMediaType(t *testing.T) {
+	ii, err := IndexFromStruct(ImageIndex{
+		Versioned: IndexSchemaVersion,
+		Manifests: []ManifestDescriptor{
+			{
+				Descriptor: distribution.Descriptor{MediaType: MediaTypeImageManifest, Digest: "sha256:amd64"},
+				Platform:   PlatformSpec{OS: "linux", Architecture: "amd64"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("IndexFromStruct: %v", err)
+	}
+
+	_, payload, err := ii.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+
+	var roundTripped DeserializedImageIndex
+	if err := roundTripped.UnmarshalJSON(payload); err != nil {
+		t.Fatalf("UnmarshalJSON should accept an index with the correct media type: %v", err)
+	}
+
+	wrongType := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`)
+	var rejected DeserializedImageIndex
+	if err := rejected.UnmarshalJSON(wrongType); err == nil {
+		t.Fatal("UnmarshalJSON should reject an index whose mediaType isn't MediaTypeImageIndex")
+	}
+}
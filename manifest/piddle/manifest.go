@@ -10,11 +10,14 @@ import (
 )
 
 const (
+	// 单平台 Image Manifest（config+layers）自身的媒体类型；请求/返回该文档时带的Accept/Content-Type
+	MediaTypeImageManifest = "application/vnd.piddle.image.manifest.v1+json"
+
 	// 请求 Manifest List（Image Index） 时带的Accept；返回时带的Content-Type
 	MediaTypeImageIndex = "application/vnd.piddle.image.index.v1+json"
 
 	// Image Manifest 中的config，用以索引image config blob
-	MediaTypeImageConfig = "application/vnd.piddle.image.manifest.v1+json"
+	MediaTypeImageConfig = "application/vnd.piddle.image.config.v1+json"
 
 	// Image Manifest 中的layers，用以索引layer.tar blob
 	MediaTypeLayer = "application/vnd.piddle.image.layer.v1.tar"
@@ -40,7 +43,7 @@ var (
 	// packages version of the manifest.
 	SchemaVersion = manifest.Versioned{
 		SchemaVersion: 1,
-		MediaType:     MediaTypeImageIndex,
+		MediaType:     MediaTypeImageManifest,
 	}
 )
 
@@ -53,11 +56,24 @@ func init() {
 			return nil, distribution.Descriptor{}, err
 		}
 
+		dgst := digest.FromBytes(b)
+		return m, distribution.Descriptor{Digest: dgst, Size: int64(len(b)), MediaType: MediaTypeImageManifest}, err
+	}
+	if err := distribution.RegisterManifestSchema(MediaTypeImageManifest, schema2Func); err != nil {
+		panic(fmt.Sprintf("Unable to register manifest: %s", err))
+	}
+
+	indexFunc := func(b []byte) (distribution.Manifest, distribution.Descriptor, error) {
+		m := new(DeserializedImageIndex)
+		err := m.UnmarshalJSON(b)
+		if err != nil {
+			return nil, distribution.Descriptor{}, err
+		}
+
 		dgst := digest.FromBytes(b)
 		return m, distribution.Descriptor{Digest: dgst, Size: int64(len(b)), MediaType: MediaTypeImageIndex}, err
 	}
-	err := distribution.RegisterManifestSchema(MediaTypeImageIndex, schema2Func)
-	if err != nil {
+	if err := distribution.RegisterManifestSchema(MediaTypeImageIndex, indexFunc); err != nil {
 		panic(fmt.Sprintf("Unable to register manifest: %s", err))
 	}
 }
@@ -78,6 +94,10 @@ type Manifest struct {
 	// Layers lists descriptors for the layers referenced by the
 	// configuration.
 	Layers []distribution.Descriptor `json:"layers"`
+
+	// Annotations 是该manifest的任意附加KV信息。
+	// Annotations contains arbitrary metadata for the image manifest.
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // References returns the descriptors of this manifests references.
@@ -125,17 +145,46 @@ func (m *DeserializedManifest) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	if manifest.MediaType != MediaTypeImageIndex {
+	if manifest.MediaType != MediaTypeImageManifest {
 		return fmt.Errorf("mediaType in manifest should be '%s' not '%s'",
-			MediaTypeImageIndex, manifest.MediaType)
+			MediaTypeImageManifest, manifest.MediaType)
 
 	}
 
+	if err := validateLayerURLs(manifest.Layers); err != nil {
+		return err
+	}
+
 	m.Manifest = manifest
 
 	return nil
 }
 
+// isNondistributableLayerMediaType 判断mediaType是否为不可被push到registry的layer类型
+func isNondistributableLayerMediaType(mediaType string) bool {
+	switch mediaType {
+	case MediaTypeNondistributableLayer, MediaTypeNondistributableLayerGzip, MediaTypeNondistributableLayerZstd:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateLayerURLs 校验layers的URLs：非distributable的layer必须携带URLs，
+// 这是NBD-URL层级间接引用的基础；可distributable的layer不得携带外部URLs。
+func validateLayerURLs(layers []distribution.Descriptor) error {
+	for _, l := range layers {
+		nondistributable := isNondistributableLayerMediaType(l.MediaType)
+		if nondistributable && len(l.URLs) == 0 {
+			return fmt.Errorf("mediaType %s is non-distributable and must have at least one URL", l.MediaType)
+		}
+		if !nondistributable && isLayerMediaType(l.MediaType) && len(l.URLs) != 0 {
+			return fmt.Errorf("mediaType %s is distributable and must not have foreign URLs", l.MediaType)
+		}
+	}
+	return nil
+}
+
 // MarshalJSON returns the contents of canonical. If canonical is empty,
 // marshals the inner contents.
 func (m *DeserializedManifest) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,87 @@
+package piddle
+
+import (
+	"testing"
+
+	"github.com/sequix/distribution"
+)
+
+func TestValidateLayerURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		layers  []distribution.Descriptor
+		wantErr bool
+	}{
+		{
+			name: "distributable layer without URLs is fine",
+			layers: []distribution.Descriptor{
+				{MediaType: MediaTypeLayer},
+			},
+		},
+		{
+			name: "non-distributable layer with URLs is fine",
+			layers: []distribution.Descriptor{
+				{MediaType: MediaTypeNondistributableLayerGzip, URLs: []string{"nbd://host/vol"}},
+			},
+		},
+		{
+			name: "non-distributable layer without URLs is rejected",
+			layers: []distribution.Descriptor{
+				{MediaType: MediaTypeNondistributableLayerZstd},
+			},
+			wantErr: true,
+		},
+		{
+			name: "distributable layer with foreign URLs is rejected",
+			layers: []distribution.Descriptor{
+				{MediaType: MediaTypeLayerGzip, URLs: []string{"https://example.com/layer"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "non-layer media types are left alone",
+			layers: []distribution.Descriptor{
+				{MediaType: MediaTypeImageConfig},
+			},
+		},
+		{
+			name: "mix of valid layers",
+			layers: []distribution.Descriptor{
+				{MediaType: MediaTypeLayer},
+				{MediaType: MediaTypeNondistributableLayer, URLs: []string{"nbd://host/vol"}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateLayerURLs(tc.layers)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateLayerURLs(%+v) error = %v, wantErr %v", tc.layers, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeserializedManifestUnmarshalJSONRejectsInvalidLayerURLs(t *testing.T) {
+	m, err := FromStruct(Manifest{
+		Versioned: SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: MediaTypeImageConfig, Digest: "sha256:aaaa", Size: 2},
+		Layers: []distribution.Descriptor{
+			{MediaType: MediaTypeNondistributableLayer, Digest: "sha256:bbbb", Size: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FromStruct: %v", err)
+	}
+
+	_, payload, err := m.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+
+	var roundTripped DeserializedManifest
+	if err := roundTripped.UnmarshalJSON(payload); err == nil {
+		t.Fatal("UnmarshalJSON should reject a non-distributable layer without URLs")
+	}
+}
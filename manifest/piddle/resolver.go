@@ -0,0 +1,66 @@
+package piddle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/artifact"
+)
+
+func init() {
+	if err := artifact.RegisterResolver(MediaTypeImageManifest, imageResolver{}); err != nil {
+		panic(fmt.Sprintf("Unable to register artifact resolver: %s", err))
+	}
+}
+
+// ImageConfig 是从config blob中解出的、resolver关心的最小元数据子集。
+type ImageConfig struct {
+	Architecture string            `json:"architecture"`
+	OS           string            `json:"os"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// imageArtifact is the artifact.Artifact implementation for piddle images.
+type imageArtifact struct {
+	manifest Manifest
+	config   ImageConfig
+}
+
+func (a imageArtifact) Kind() artifact.Kind { return artifact.KindImage }
+
+func (a imageArtifact) Tags() []string { return nil }
+
+func (a imageArtifact) References() []distribution.Descriptor { return a.manifest.References() }
+
+func (a imageArtifact) Extra() map[string]interface{} {
+	return map[string]interface{}{
+		"architecture": a.config.Architecture,
+		"os":           a.config.OS,
+		"labels":       a.config.Labels,
+	}
+}
+
+// imageResolver resolves a piddle image Manifest into an imageArtifact by
+// fetching and unpacking its config blob.
+type imageResolver struct{}
+
+func (imageResolver) Resolve(ctx context.Context, repo distribution.Repository, m distribution.Manifest) (artifact.Artifact, error) {
+	pm, ok := m.(*DeserializedManifest)
+	if !ok {
+		return nil, fmt.Errorf("piddle: resolver expects *DeserializedManifest, got %T", m)
+	}
+
+	blob, err := repo.Blobs(ctx).Get(ctx, pm.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg ImageConfig
+	if err := json.Unmarshal(blob, &cfg); err != nil {
+		return nil, err
+	}
+
+	return imageArtifact{manifest: pm.Manifest, config: cfg}, nil
+}
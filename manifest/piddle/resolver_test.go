@@ -0,0 +1,109 @@
+package piddle
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/artifact"
+	"github.com/sequix/distribution/reference"
+)
+
+// fakeGetBlobService实现distribution.BlobService，只有Get是imageResolver关心
+// 的路径，其余方法从不被调用。
+type fakeGetBlobService struct {
+	get func(ctx context.Context, dgst digest.Digest) ([]byte, error)
+}
+
+func (f fakeGetBlobService) Stat(ctx context.Context, dgst digest.Digest) (distribution.Descriptor, error) {
+	return distribution.Descriptor{}, fmt.Errorf("fakeGetBlobService: Stat not implemented")
+}
+
+func (f fakeGetBlobService) Get(ctx context.Context, dgst digest.Digest) ([]byte, error) {
+	return f.get(ctx, dgst)
+}
+
+func (f fakeGetBlobService) Put(ctx context.Context, mediaType string, p []byte) (distribution.Descriptor, error) {
+	return distribution.Descriptor{}, fmt.Errorf("fakeGetBlobService: Put not implemented")
+}
+
+func (f fakeGetBlobService) Writer(ctx context.Context, options ...distribution.BlobWriteOption) (distribution.BlobWriter, error) {
+	return nil, fmt.Errorf("fakeGetBlobService: Writer not implemented")
+}
+
+// fakeRepository实现distribution.Repository，只有Blobs是imageResolver/
+// bundleResolver关心的路径，其余方法从不被调用。
+type fakeRepository struct {
+	blobs distribution.BlobService
+}
+
+func (f fakeRepository) Named() reference.Named {
+	return nil
+}
+
+func (f fakeRepository) Manifests(ctx context.Context, options ...distribution.ManifestServiceOption) (distribution.ManifestService, error) {
+	return nil, fmt.Errorf("fakeRepository: Manifests not implemented")
+}
+
+func (f fakeRepository) Blobs(ctx context.Context) distribution.BlobService {
+	return f.blobs
+}
+
+func (f fakeRepository) Tags(ctx context.Context) distribution.TagService {
+	return nil
+}
+
+func TestImageResolverResolve(t *testing.T) {
+	configJSON := []byte(`{"architecture":"amd64","os":"linux","labels":{"com.example":"1"}}`)
+	configDigest := digest.FromBytes(configJSON)
+
+	m, err := FromStruct(Manifest{
+		Versioned: SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: MediaTypeImageConfig, Digest: configDigest, Size: int64(len(configJSON))},
+		Layers: []distribution.Descriptor{
+			{MediaType: MediaTypeLayerGzip, Digest: "sha256:aaaa", Size: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FromStruct: %v", err)
+	}
+
+	repo := fakeRepository{
+		blobs: fakeGetBlobService{
+			get: func(ctx context.Context, dgst digest.Digest) ([]byte, error) {
+				if dgst != configDigest {
+					t.Fatalf("Get called with digest %q, want %q", dgst, configDigest)
+				}
+				return configJSON, nil
+			},
+		},
+	}
+
+	a, err := artifact.Resolve(context.Background(), repo, m)
+	if err != nil {
+		t.Fatalf("artifact.Resolve: %v", err)
+	}
+
+	if a.Kind() != artifact.KindImage {
+		t.Errorf("Kind() = %q, want %q", a.Kind(), artifact.KindImage)
+	}
+	if len(a.References()) != 2 {
+		t.Errorf("References() = %+v, want config + 1 layer", a.References())
+	}
+	extra := a.Extra()
+	if extra["architecture"] != "amd64" || extra["os"] != "linux" {
+		t.Errorf("Extra() = %+v, want architecture=amd64 os=linux", extra)
+	}
+}
+
+func TestImageResolverResolveWrongManifestType(t *testing.T) {
+	index, err := FromDescriptors(nil)
+	if err != nil {
+		t.Fatalf("FromDescriptors: %v", err)
+	}
+	if _, err := (imageResolver{}).Resolve(context.Background(), fakeRepository{}, index); err == nil {
+		t.Fatal("Resolve should error when given a manifest it doesn't own")
+	}
+}
@@ -137,3 +137,58 @@ func RegisterManifestSchema(mediaType string, u UnmarshalFunc) error {
 	mappings[mediaType] = u
 	return nil
 }
+
+// ManifestConverter 把一个Manifest转换为另一种media type的等价Manifest。
+// ManifestConverter converts a manifest from one media type to another.
+type ManifestConverter func(Manifest) (Manifest, error)
+
+// converterKey对(from, to)这对media type做索引。
+type converterKey struct {
+	from, to string
+}
+
+// converters按(from, to)这对media type索引ManifestConverter。
+var converters = make(map[converterKey]ManifestConverter)
+
+// RegisterManifestConverter registers a converter capable of turning a
+// manifest with media type `from` into one with media type `to`. This lets a
+// registry serve a manifest stored in one format to a client requesting
+// another, without the client needing to know which format is on disk.
+func RegisterManifestConverter(from, to string, fn ManifestConverter) error {
+	key := converterKey{from, to}
+	if _, ok := converters[key]; ok {
+		return fmt.Errorf("manifest converter registration would overwrite existing: %s -> %s", from, to)
+	}
+	converters[key] = fn
+	return nil
+}
+
+// ErrManifestConversionUnsupported is returned by ConvertManifest when no
+// converter is registered for the requested (from, to) media type pair.
+type ErrManifestConversionUnsupported struct {
+	From, To string
+}
+
+func (e ErrManifestConversionUnsupported) Error() string {
+	return fmt.Sprintf("no manifest converter registered from %q to %q", e.From, e.To)
+}
+
+// ConvertManifest converts m to the given target media type using a
+// registered ManifestConverter. If m is already of the target media type, m
+// is returned unchanged.
+func ConvertManifest(m Manifest, to string) (Manifest, error) {
+	from, _, err := m.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	if from == to {
+		return m, nil
+	}
+
+	fn, ok := converters[converterKey{from, to}]
+	if !ok {
+		return nil, ErrManifestConversionUnsupported{From: from, To: to}
+	}
+	return fn(m)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/artifact"
 	dcontext "github.com/sequix/distribution/context"
 	"github.com/sequix/distribution/registry/api/errcode"
 	"github.com/sequix/distribution/registry/api/v2"
@@ -28,6 +29,14 @@ type Context struct {
 	// RepositoryRemover provides method to delete a repository
 	RepositoryRemover distribution.RepositoryRemover
 
+	// Artifact is the artifact.Artifact resolved from the manifest targeted
+	// by the current request, if any. It lets handlers (list-by-kind, filter
+	// tags by artifact type, ...) consume artifact metadata without
+	// special-casing each manifest media type. This field may be nil when
+	// the request isn't scoped to a single manifest or no resolver is
+	// registered for its media type.
+	Artifact artifact.Artifact
+
 	// Errors is a collection of errors encountered during the request to be
 	// returned to the client API. If errors are added to the collection, the
 	// handler *must not* start the response via http.ResponseWriter.
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/artifact"
+	dcontext "github.com/sequix/distribution/context"
+	"github.com/sequix/distribution/manifest/ocischema"
+	"github.com/sequix/distribution/manifest/piddle"
+	"github.com/sequix/distribution/registry/api/errcode"
+	"github.com/sequix/distribution/registry/api/v2"
+)
+
+// errcodeManifestNotAcceptable是本包私有的errcode，用来给negotiateManifest的
+// 406场景一个专属的ErrorCode：registry/api/v2目前没有预置"manifest不可接受"
+// 这个语义，参照distribution/registry/auth等包就地用errcode.Register()注册
+// 私有错误码的先例，在这里注册一个。
+var errcodeManifestNotAcceptable = errcode.Register("registry.api.v2", errcode.ErrorDescriptor{
+	Value:          "MANIFEST_NOT_ACCEPTABLE",
+	Message:        "manifest not acceptable",
+	Description:    "Returned when none of the client's Accept media types match the stored manifest and no registered converter can bridge the gap.",
+	HTTPStatusCode: http.StatusNotAcceptable,
+})
+
+// manifestHandler 处理单个manifest的GET请求。
+type manifestHandler struct {
+	*Context
+}
+
+// platformFromRequest 从请求的查询参数里读取客户端想要的平台，ok为false表示
+// 客户端没有指定平台（比如直接请求单个image manifest，而不是image index）。
+func platformFromRequest(r *http.Request) (os, architecture, variant string, ok bool) {
+	q := r.URL.Query()
+	os, architecture, variant = q.Get("os"), q.Get("architecture"), q.Get("variant")
+	return os, architecture, variant, os != "" && architecture != ""
+}
+
+// resolveManifest实现GetManifest的核心逻辑：若m是一个image index（piddle原生，
+// 或可转换为piddle的OCI image index）且请求指定了平台，用ImageIndex.Select挑出
+// 匹配的child manifest并通过get取回其内容。OCI index先转换成piddle的
+// DeserializedImageIndex再做选择，这样platform选择逻辑只需要维护一份。
+// 拆成一个不直接依赖distribution.ManifestService的函数，是为了能在没有完整
+// distribution.Repository实现的情况下对平台选择这部分逻辑做单元测试。
+func resolveManifest(r *http.Request, m distribution.Manifest, get func(digest.Digest) (distribution.Manifest, error)) (distribution.Manifest, error) {
+	index, ok := m.(*piddle.DeserializedImageIndex)
+	if !ok {
+		ociIndex, ok := m.(*ocischema.DeserializedImageIndex)
+		if !ok {
+			return m, nil
+		}
+
+		var err error
+		index, err = piddle.FromOCIIndex(ociIndex)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	os, architecture, variant, ok := platformFromRequest(r)
+	if !ok {
+		return m, nil
+	}
+
+	md, found := index.Select(os, architecture, variant)
+	if !found {
+		return nil, fmt.Errorf("no manifest for platform %s/%s/%s in index", os, architecture, variant)
+	}
+
+	return get(md.Digest)
+}
+
+// resolveArtifact在ctx.Repository上为m解析artifact元数据并挂到ctx.Artifact。
+// 没有为m的media type注册Resolver是正常情况（不是所有manifest都声明了自己的
+// artifact kind），此时ctx.Artifact保持为nil，不当作错误处理；但Resolver本身
+// 执行失败（比如读取config blob出错）是异常情况，需要记录下来而不是静默丢弃。
+func resolveArtifact(ctx *Context, m distribution.Manifest) {
+	a, err := artifact.Resolve(ctx, ctx.Repository, m)
+	if err != nil {
+		if _, ok := err.(artifact.ErrNoResolver); !ok {
+			dcontext.GetLogger(ctx).Errorf("error resolving artifact: %v", err)
+		}
+		return
+	}
+	ctx.Artifact = a
+}
+
+// GetManifest实现manifest的GET请求：按digest取出存储的manifest，挑选客户端
+// 请求的平台（如果适用），再把结果序列化返回。出错时把errcode.Error追加到
+// ctx.Errors，不直接操作http.ResponseWriter，由上层dispatcher统一序列化响应。
+func (mh *manifestHandler) GetManifest(w http.ResponseWriter, r *http.Request) {
+	ctx := mh.Context
+
+	dgst, err := getDigest(ctx)
+	if err != nil {
+		ctx.Errors = append(ctx.Errors, v2.ErrorCodeDigestInvalid.WithDetail(err))
+		return
+	}
+
+	manifests, err := ctx.Repository.Manifests(ctx)
+	if err != nil {
+		ctx.Errors = append(ctx.Errors, errcode.ErrorCodeUnknown.WithDetail(err))
+		return
+	}
+
+	m, err := manifests.Get(ctx, dgst)
+	if err != nil {
+		ctx.Errors = append(ctx.Errors, v2.ErrorCodeManifestUnknown.WithDetail(err))
+		return
+	}
+
+	resolved, err := resolveManifest(r, m, func(d digest.Digest) (distribution.Manifest, error) {
+		return manifests.Get(ctx, d)
+	})
+	if err != nil {
+		ctx.Errors = append(ctx.Errors, v2.ErrorCodeManifestUnknown.WithDetail(err))
+		return
+	}
+
+	resolveArtifact(ctx, resolved)
+
+	negotiated, err := negotiateManifest(r, resolved)
+	if err != nil {
+		if err == errManifestNotAcceptable {
+			ctx.Errors = append(ctx.Errors, errcodeManifestNotAcceptable.WithDetail(err))
+			return
+		}
+		ctx.Errors = append(ctx.Errors, errcode.ErrorCodeUnknown.WithDetail(err))
+		return
+	}
+
+	mediaType, payload, err := negotiated.Payload()
+	if err != nil {
+		ctx.Errors = append(ctx.Errors, errcode.ErrorCodeUnknown.WithDetail(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Write(payload)
+}
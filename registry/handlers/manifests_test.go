@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/artifact"
+	"github.com/sequix/distribution/manifest/ocischema"
+	"github.com/sequix/distribution/manifest/piddle"
+)
+
+func sampleIndex(t *testing.T) (*piddle.DeserializedImageIndex, digest.Digest) {
+	t.Helper()
+
+	const amd64Digest = digest.Digest("sha256:" + "a111111111111111111111111111111111111111111111111111111111111")
+
+	index, err := piddle.FromDescriptors([]piddle.ManifestDescriptor{
+		{
+			Descriptor: distribution.Descriptor{MediaType: piddle.MediaTypeImageManifest, Digest: amd64Digest, Size: 4},
+			Platform:   piddle.PlatformSpec{OS: "linux", Architecture: "amd64"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("piddle.FromDescriptors: %v", err)
+	}
+	return index, amd64Digest
+}
+
+func TestResolveManifestSelectsRequestedPlatform(t *testing.T) {
+	index, amd64Digest := sampleIndex(t)
+	child := samplePiddleManifest(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/?os=linux&architecture=amd64", nil)
+	got, err := resolveManifest(r, index, func(d digest.Digest) (distribution.Manifest, error) {
+		if d != amd64Digest {
+			t.Fatalf("get called with digest %q, want %q", d, amd64Digest)
+		}
+		return child, nil
+	})
+	if err != nil {
+		t.Fatalf("resolveManifest: %v", err)
+	}
+	if got != child {
+		t.Errorf("got %v, want the resolved child manifest", got)
+	}
+}
+
+func TestResolveManifestNoPlatformReturnsIndexUnchanged(t *testing.T) {
+	index, _ := sampleIndex(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	got, err := resolveManifest(r, index, func(d digest.Digest) (distribution.Manifest, error) {
+		t.Fatal("get should not be called when no platform is requested")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("resolveManifest: %v", err)
+	}
+	if got != index {
+		t.Errorf("got %v, want the index unchanged", got)
+	}
+}
+
+func TestResolveManifestUnknownPlatformErrors(t *testing.T) {
+	index, _ := sampleIndex(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/?os=windows&architecture=amd64", nil)
+	if _, err := resolveManifest(r, index, func(d digest.Digest) (distribution.Manifest, error) {
+		t.Fatal("get should not be called for an unmatched platform")
+		return nil, nil
+	}); err == nil {
+		t.Fatal("resolveManifest should error when no manifest matches the requested platform")
+	}
+}
+
+func TestResolvedManifestGoesThroughNegotiation(t *testing.T) {
+	index, amd64Digest := sampleIndex(t)
+	child := samplePiddleManifest(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/?os=linux&architecture=amd64", nil)
+	r.Header.Set("Accept", "application/vnd.cnab.manifest.v1+json")
+
+	resolved, err := resolveManifest(r, index, func(d digest.Digest) (distribution.Manifest, error) {
+		if d != amd64Digest {
+			t.Fatalf("get called with digest %q, want %q", d, amd64Digest)
+		}
+		return child, nil
+	})
+	if err != nil {
+		t.Fatalf("resolveManifest: %v", err)
+	}
+
+	// Once platform-selected, the resolved child manifest still has to pass
+	// through negotiateManifest before being served: an Accept header with
+	// no matching type or converter must still be rejected.
+	if _, err := negotiateManifest(r, resolved); err != errManifestNotAcceptable {
+		t.Fatalf("negotiateManifest(resolved) error = %v, want errManifestNotAcceptable", err)
+	}
+}
+
+// resolveArtifactStubManifest is a minimal distribution.Manifest whose media
+// type is all resolveArtifact (via artifact.Resolve) ever inspects.
+type resolveArtifactStubManifest struct {
+	mediaType string
+}
+
+func (m resolveArtifactStubManifest) References() []distribution.Descriptor { return nil }
+
+func (m resolveArtifactStubManifest) Payload() (string, []byte, error) {
+	return m.mediaType, nil, nil
+}
+
+// resolveArtifactStubResolver returns a fixed (artifact, error) pair,
+// ignoring its arguments.
+type resolveArtifactStubResolver struct {
+	artifact artifact.Artifact
+	err      error
+}
+
+func (r resolveArtifactStubResolver) Resolve(ctx context.Context, repo distribution.Repository, m distribution.Manifest) (artifact.Artifact, error) {
+	return r.artifact, r.err
+}
+
+type resolveArtifactStubArtifact struct{}
+
+func (resolveArtifactStubArtifact) Kind() artifact.Kind                   { return artifact.Kind("stub") }
+func (resolveArtifactStubArtifact) Tags() []string                        { return nil }
+func (resolveArtifactStubArtifact) References() []distribution.Descriptor { return nil }
+func (resolveArtifactStubArtifact) Extra() map[string]interface{}         { return nil }
+
+func TestResolveArtifactNoResolverLeavesArtifactNil(t *testing.T) {
+	m := resolveArtifactStubManifest{mediaType: "application/vnd.sequix.manifests-test.no-resolver.v1+json"}
+
+	ctx := &Context{Context: context.Background()}
+	resolveArtifact(ctx, m)
+
+	if ctx.Artifact != nil {
+		t.Errorf("Artifact = %+v, want nil when no resolver is registered for the media type", ctx.Artifact)
+	}
+}
+
+func TestResolveArtifactSetsArtifactOnSuccess(t *testing.T) {
+	const mediaType = "application/vnd.sequix.manifests-test.ok-resolver.v1+json"
+	want := resolveArtifactStubArtifact{}
+	if err := artifact.RegisterResolver(mediaType, resolveArtifactStubResolver{artifact: want}); err != nil {
+		t.Fatalf("RegisterResolver: %v", err)
+	}
+	m := resolveArtifactStubManifest{mediaType: mediaType}
+
+	ctx := &Context{Context: context.Background()}
+	resolveArtifact(ctx, m)
+
+	if ctx.Artifact != artifact.Artifact(want) {
+		t.Errorf("Artifact = %+v, want %+v", ctx.Artifact, want)
+	}
+}
+
+func TestResolveArtifactSwallowsResolverErrorWithoutSettingArtifact(t *testing.T) {
+	const mediaType = "application/vnd.sequix.manifests-test.broken-resolver.v1+json"
+	if err := artifact.RegisterResolver(mediaType, resolveArtifactStubResolver{err: fmt.Errorf("boom")}); err != nil {
+		t.Fatalf("RegisterResolver: %v", err)
+	}
+	m := resolveArtifactStubManifest{mediaType: mediaType}
+
+	ctx := &Context{Context: context.Background()}
+	resolveArtifact(ctx, m)
+
+	if ctx.Artifact != nil {
+		t.Errorf("Artifact = %+v, want nil when the resolver itself errors", ctx.Artifact)
+	}
+}
+
+func TestResolveManifestSelectsRequestedPlatformForOCIIndex(t *testing.T) {
+	const amd64Digest = digest.Digest("sha256:" + "b222222222222222222222222222222222222222222222222222222222222")
+
+	index, err := ocischema.FromDescriptors([]ocischema.ManifestDescriptor{
+		{
+			Descriptor: distribution.Descriptor{MediaType: ocischema.MediaTypeImageManifest, Digest: amd64Digest, Size: 4},
+			Platform:   ocischema.PlatformSpec{OS: "linux", Architecture: "amd64"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ocischema.FromDescriptors: %v", err)
+	}
+	child := samplePiddleManifest(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/?os=linux&architecture=amd64", nil)
+	got, err := resolveManifest(r, index, func(d digest.Digest) (distribution.Manifest, error) {
+		if d != amd64Digest {
+			t.Fatalf("get called with digest %q, want %q", d, amd64Digest)
+		}
+		return child, nil
+	})
+	if err != nil {
+		t.Fatalf("resolveManifest: %v", err)
+	}
+	if got != child {
+		t.Errorf("got %v, want the resolved child manifest", got)
+	}
+}
+
+func TestResolveManifestPassesThroughNonIndexManifests(t *testing.T) {
+	m := samplePiddleManifest(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/?os=linux&architecture=amd64", nil)
+	got, err := resolveManifest(r, m, func(d digest.Digest) (distribution.Manifest, error) {
+		t.Fatal("get should not be called for a non-index manifest")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("resolveManifest: %v", err)
+	}
+	if got != m {
+		t.Errorf("got %v, want the manifest unchanged", got)
+	}
+}
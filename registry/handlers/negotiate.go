@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sequix/distribution"
+)
+
+// errManifestNotAcceptable is returned by negotiateManifest when none of the
+// client's Accept media types match the stored manifest and no registered
+// distribution.ManifestConverter can bridge the gap. Callers (the manifest
+// GET handler) should map this to a 406 Not Acceptable errcode response.
+var errManifestNotAcceptable = fmt.Errorf("no acceptable manifest media type")
+
+// acceptEntry is one parsed entry of an Accept header.
+type acceptEntry struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept 解析一条Accept header，返回按q值从高到低排序的media type列表；
+// 没有带q参数的条目默认为q=1。
+func parseAccept(header string) []acceptEntry {
+	var entries []acceptEntry
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(qs, 64); err == nil {
+				q = parsed
+			}
+		}
+		entries = append(entries, acceptEntry{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+	return entries
+}
+
+// negotiateManifest 按请求的Accept header挑选最合适的media type返回m：按q值
+// 从高到低（跳过q<=0的条目）逐个检查，条目等于m本身的media type或为*/*时原样
+// 返回m，否则尝试用已注册的distribution.ManifestConverter把m转换为该类型，
+// 第一个成功的即返回；因此q值更高的可转换类型优先于q值更低的存储类型。都不行则
+// 返回errManifestNotAcceptable。
+func negotiateManifest(r *http.Request, m distribution.Manifest) (distribution.Manifest, error) {
+	storedType, _, err := m.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []acceptEntry
+	for _, header := range r.Header["Accept"] {
+		entries = append(entries, parseAccept(header)...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+
+	// 没有带Accept header时保持原有行为，直接返回存储的manifest。
+	if len(entries) == 0 {
+		return m, nil
+	}
+
+	for _, e := range entries {
+		if e.q <= 0 {
+			continue
+		}
+		if e.mediaType == storedType || e.mediaType == "*/*" {
+			return m, nil
+		}
+		if converted, err := distribution.ConvertManifest(m, e.mediaType); err == nil {
+			return converted, nil
+		}
+	}
+
+	return nil, errManifestNotAcceptable
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/sequix/distribution"
+	"github.com/sequix/distribution/manifest/ocischema"
+	"github.com/sequix/distribution/manifest/piddle"
+)
+
+func samplePiddleManifest(t *testing.T) distribution.Manifest {
+	t.Helper()
+
+	m, err := piddle.FromStruct(piddle.Manifest{
+		Versioned: piddle.SchemaVersion,
+		Config:    distribution.Descriptor{MediaType: piddle.MediaTypeImageConfig, Digest: "sha256:aaaa", Size: 2},
+		Layers: []distribution.Descriptor{
+			{MediaType: piddle.MediaTypeLayerGzip, Digest: "sha256:bbbb", Size: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("piddle.FromStruct: %v", err)
+	}
+	return m
+}
+
+func TestParseAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []acceptEntry
+	}{
+		{
+			name:   "single type defaults to q=1",
+			header: "application/json",
+			want:   []acceptEntry{{mediaType: "application/json", q: 1}},
+		},
+		{
+			name:   "explicit q values are honored and sorted descending",
+			header: "application/vnd.oci.image.manifest.v1+json;q=0.5, application/vnd.piddle.image.manifest.v1+json;q=0.9",
+			want: []acceptEntry{
+				{mediaType: "application/vnd.piddle.image.manifest.v1+json", q: 0.9},
+				{mediaType: "application/vnd.oci.image.manifest.v1+json", q: 0.5},
+			},
+		},
+		{
+			name:   "invalid entries are skipped",
+			header: "not a media type, application/json",
+			want:   []acceptEntry{{mediaType: "application/json", q: 1}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseAccept(tc.header)
+			if len(got) != len(tc.want) {
+				t.Fatalf("parseAccept(%q) = %+v, want %+v", tc.header, got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("entry %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestNegotiateManifest exercises negotiateManifest directly, covering the
+// content-negotiation logic in isolation from manifestHandler.GetManifest
+// (registry/handlers/manifests.go), which is what actually invokes it on
+// the request path.
+func TestNegotiateManifest(t *testing.T) {
+	m := samplePiddleManifest(t)
+
+	t.Run("no Accept header returns the stored manifest unchanged", func(t *testing.T) {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		got, err := negotiateManifest(r, m)
+		if err != nil {
+			t.Fatalf("negotiateManifest: %v", err)
+		}
+		if got != m {
+			t.Errorf("got %v, want the original manifest unchanged", got)
+		}
+	})
+
+	t.Run("stored media type present in Accept is returned unchanged", func(t *testing.T) {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", piddle.MediaTypeImageManifest)
+		got, err := negotiateManifest(r, m)
+		if err != nil {
+			t.Fatalf("negotiateManifest: %v", err)
+		}
+		if got != m {
+			t.Errorf("got %v, want the original manifest unchanged", got)
+		}
+	})
+
+	t.Run("higher q-value OCI type triggers conversion", func(t *testing.T) {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", ocischema.MediaTypeImageManifest+";q=0.9, "+piddle.MediaTypeImageManifest+";q=0.1")
+		got, err := negotiateManifest(r, m)
+		if err != nil {
+			t.Fatalf("negotiateManifest: %v", err)
+		}
+		gotType, _, err := got.Payload()
+		if err != nil {
+			t.Fatalf("Payload: %v", err)
+		}
+		if gotType != ocischema.MediaTypeImageManifest {
+			t.Errorf("negotiated media type = %q, want %q", gotType, ocischema.MediaTypeImageManifest)
+		}
+	})
+
+	t.Run("no acceptable type and no converter falls back to 406", func(t *testing.T) {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "application/vnd.cnab.manifest.v1+json")
+		if _, err := negotiateManifest(r, m); err != errManifestNotAcceptable {
+			t.Errorf("err = %v, want errManifestNotAcceptable", err)
+		}
+	})
+
+	t.Run("q=0 entries are never selected", func(t *testing.T) {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", ocischema.MediaTypeImageManifest+";q=0")
+		if _, err := negotiateManifest(r, m); err != errManifestNotAcceptable {
+			t.Errorf("err = %v, want errManifestNotAcceptable", err)
+		}
+	})
+}